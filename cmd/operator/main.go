@@ -0,0 +1,92 @@
+// Command operator is the entrypoint for the Windows Machine Config Operator.
+package main
+
+import (
+	"context"
+	"os"
+
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	crclientcfg "sigs.k8s.io/controller-runtime/pkg/client/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/controller/payload"
+	"github.com/openshift/windows-machine-config-operator/pkg/controller/windowsmachine/byoh"
+	"github.com/openshift/windows-machine-config-operator/pkg/controller/windowsmachine/nodeconfig"
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
+)
+
+const (
+	// wmcoNamespace is the namespace the operator and the resources it manages run in.
+	wmcoNamespace = "openshift-windows-machine-config-operator"
+	// defaultVXLANPort is the VXLAN port the windows services ConfigMap is generated for and BYOH Windows
+	// nodes are configured with. No cluster-specific VXLAN port is read.
+	defaultVXLANPort = "4789"
+)
+
+var log = logf.Log.WithName("operator")
+
+func main() {
+	logf.SetLogger(zap.New())
+
+	cfg, err := crclientcfg.GetConfig()
+	if err != nil {
+		log.Error(err, "unable to get client config")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Namespace: wmcoNamespace})
+	if err != nil {
+		log.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	// Materialize the cni-conf-template.ps1 payload script the operator image ships with into the cluster
+	// before any controller begins reconciling Windows nodes, so CNIProvider.RenderConfig always has the
+	// script available to push to a VM, rather than rendering it lazily on the first node it configures.
+	if err := payload.PopulateNetworkConfScript(wmcoNamespace); err != nil {
+		log.Error(err, "unable to populate network conf script")
+		os.Exit(1)
+	}
+
+	networkType, err := nodeconfig.DiscoverNetworkType()
+	if err != nil {
+		log.Error(err, "unable to discover cluster network type")
+		os.Exit(1)
+	}
+	overlayServices, err := nodeconfig.OverlayServicesFor(networkType)
+	if err != nil {
+		log.Error(err, "unable to determine overlay services for cluster network type")
+		os.Exit(1)
+	}
+	// dsrEnabled is conservatively set to false at startup: whether the node's HNS supports DSR can only be
+	// probed from the Windows VM itself, which nodeConfig.Configure already does per-node, but the services
+	// manifest reconciled here is shared cluster-wide, so it cannot reflect a single node's capability.
+	if _, err := services.NewConfigMapReconciler(mgr.GetClient(), wmcoNamespace).Reconcile(context.Background(),
+		defaultVXLANPort, false, overlayServices, false); err != nil {
+		log.Error(err, "unable to reconcile windows services ConfigMap")
+		os.Exit(1)
+	}
+
+	k8sclientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		log.Error(err, "unable to get kubernetes clientset")
+		os.Exit(1)
+	}
+	clusterServiceCIDR, err := nodeconfig.DiscoverClusterServiceCIDR()
+	if err != nil {
+		log.Error(err, "unable to discover cluster service CIDR")
+		os.Exit(1)
+	}
+	if err := byoh.NewReconciler(mgr, k8sclientset, clusterServiceCIDR, defaultVXLANPort,
+		wmcoNamespace).SetupWithManager(mgr); err != nil {
+		log.Error(err, "unable to set up BYOH controller")
+		os.Exit(1)
+	}
+
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		log.Error(err, "manager exited non-zero")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,177 @@
+// Package byoh implements a controller that configures user-provided ("bring your own host") Windows
+// instances as cluster nodes, driving them through the same nodeconfig.Configure path used for
+// Machine-provisioned Windows nodes.
+package byoh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/controller/windowsmachine/nodeconfig"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap listing user-provisioned Windows instances.
+	ConfigMapName = "windows-instances"
+	// usernameKey and sshSecretKey are the fields of a windows-instances ConfigMap entry.
+	usernameKey  = "username"
+	sshSecretKey = "sshSecretRef"
+)
+
+var log = logf.Log.WithName(ConfigMapName)
+
+// Reconciler configures user-provisioned Windows instances declared in the windows-instances ConfigMap as
+// cluster nodes.
+type Reconciler struct {
+	client             client.Client
+	k8sclientset       *kubernetes.Clientset
+	clusterServiceCIDR string
+	vxlanPort          string
+	namespace          string
+}
+
+// NewReconciler returns a Reconciler that drives BYOH Windows instances through nodeconfig.Configure.
+func NewReconciler(mgr ctrl.Manager, k8sclientset *kubernetes.Clientset, clusterServiceCIDR,
+	vxlanPort, namespace string) *Reconciler {
+	return &Reconciler{
+		client:             mgr.GetClient(),
+		k8sclientset:       k8sclientset,
+		clusterServiceCIDR: clusterServiceCIDR,
+		vxlanPort:          vxlanPort,
+		namespace:          namespace,
+	}
+}
+
+// SetupWithManager registers the Reconciler to watch the windows-instances ConfigMap.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&core.ConfigMap{}).
+		Complete(r)
+}
+
+// Reconcile configures each Windows instance listed in the windows-instances ConfigMap as a cluster node.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if req.Name != ConfigMapName || req.Namespace != r.namespace {
+		return ctrl.Result{}, nil
+	}
+
+	cm := &core.ConfigMap{}
+	if err := r.client.Get(ctx, req.NamespacedName, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to get windows-instances ConfigMap")
+	}
+
+	var configureErrs []error
+	for address, entry := range cm.Data {
+		instance, err := parseInstanceEntry(address, entry)
+		if err != nil {
+			log.Error(err, "skipping malformed windows-instances entry", "address", address)
+			continue
+		}
+		if err := r.configureInstance(ctx, instance); err != nil {
+			log.Error(err, "error configuring BYOH instance", "address", instance.address)
+			configureErrs = append(configureErrs, err)
+		}
+	}
+	// Returning the aggregated error, rather than swallowing it, makes controller-runtime requeue this
+	// ConfigMap with backoff, so a transient failure like a BYOH node not being registered yet gets retried
+	// without waiting on the next ConfigMap edit.
+	return ctrl.Result{}, utilerrors.NewAggregate(configureErrs)
+}
+
+// instance describes a single user-provisioned Windows host declared in the windows-instances ConfigMap.
+type instance struct {
+	address      string
+	username     string
+	sshSecretRef string
+}
+
+// parseInstanceEntry parses a windows-instances ConfigMap entry of the form
+// "username=<username>,sshSecretRef=<secret name>" keyed by the instance's address.
+func parseInstanceEntry(address, entry string) (*instance, error) {
+	inst := &instance{address: address}
+	for _, field := range strings.Split(entry, ",") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("malformed field %q", field)
+		}
+		switch kv[0] {
+		case usernameKey:
+			inst.username = kv[1]
+		case sshSecretKey:
+			inst.sshSecretRef = kv[1]
+		}
+	}
+	if inst.username == "" || inst.sshSecretRef == "" {
+		return nil, errors.Errorf("entry for %s is missing %s or %s", address, usernameKey, sshSecretKey)
+	}
+	return inst, nil
+}
+
+// configureInstance drives the given BYOH instance through the same Configure path used for
+// Machine-provisioned Windows nodes, using a synthetic provider identifier in place of a cloud ProviderID.
+func (r *Reconciler) configureInstance(ctx context.Context, inst *instance) error {
+	secret := &core.Secret{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: inst.sshSecretRef}, secret); err != nil {
+		return errors.Wrapf(err, "unable to get SSH secret %s", inst.sshSecretRef)
+	}
+	signer, err := signerFromSecret(secret)
+	if err != nil {
+		return errors.Wrap(err, "unable to parse SSH private key from secret")
+	}
+
+	if err := r.annotateNode(ctx, inst); err != nil {
+		return errors.Wrap(err, "unable to annotate node for BYOH instance")
+	}
+
+	instanceID := nodeconfig.BYOHInstanceID(inst.username, inst.address)
+	nc, err := nodeconfig.NewNodeConfig(r.k8sclientset, inst.address, instanceID, inst.address, r.clusterServiceCIDR,
+		r.vxlanPort, signer, oconfig.NonePlatformType)
+	if err != nil {
+		return errors.Wrap(err, "unable to create node config")
+	}
+	return nc.Configure()
+}
+
+// annotateNode finds the node with an internal IP matching the instance's address and stamps it with the
+// username+address annotation pair nodeconfig.setNode uses to locate BYOH nodes that have no ProviderID.
+func (r *Reconciler) annotateNode(ctx context.Context, inst *instance) error {
+	nodes := &core.NodeList{}
+	if err := r.client.List(ctx, nodes,
+		client.MatchingLabels{nodeconfig.WindowsOSLabelKey: nodeconfig.WindowsOSLabelValue}); err != nil {
+		return errors.Wrap(err, "unable to list Windows nodes")
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		for _, addr := range node.Status.Addresses {
+			if addr.Type != core.NodeInternalIP || addr.Address != inst.address {
+				continue
+			}
+			if node.Annotations[nodeconfig.UsernameAnnotation] == inst.username &&
+				node.Annotations[nodeconfig.AddressAnnotation] == inst.address {
+				return nil
+			}
+			patched := node.DeepCopy()
+			if patched.Annotations == nil {
+				patched.Annotations = map[string]string{}
+			}
+			patched.Annotations[nodeconfig.UsernameAnnotation] = inst.username
+			patched.Annotations[nodeconfig.AddressAnnotation] = inst.address
+			return r.client.Patch(ctx, patched, client.MergeFrom(node))
+		}
+	}
+	return fmt.Errorf("no node found with internal address %s", inst.address)
+}
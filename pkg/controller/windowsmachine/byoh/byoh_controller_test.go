@@ -0,0 +1,62 @@
+package byoh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/controller/windowsmachine/nodeconfig"
+)
+
+func TestParseInstanceEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		entry   string
+		wantErr bool
+		want    *instance
+	}{
+		{
+			name:    "valid entry",
+			address: "10.0.0.1",
+			entry:   "username=core,sshSecretRef=my-secret",
+			want:    &instance{address: "10.0.0.1", username: "core", sshSecretRef: "my-secret"},
+		},
+		{
+			name:    "fields in any order",
+			address: "10.0.0.1",
+			entry:   "sshSecretRef=my-secret,username=core",
+			want:    &instance{address: "10.0.0.1", username: "core", sshSecretRef: "my-secret"},
+		},
+		{
+			name:    "missing sshSecretRef",
+			address: "10.0.0.1",
+			entry:   "username=core",
+			wantErr: true,
+		},
+		{
+			name:    "malformed field",
+			address: "10.0.0.1",
+			entry:   "username",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseInstanceEntry(test.address, test.entry)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+// TestWindowsOSLabelSplit checks that nodeconfig.WindowsOSLabelKey and WindowsOSLabelValue, which annotateNode
+// uses as a client.MatchingLabels pair, compose the key=value selector string nodeconfig.WindowsOSLabel.
+func TestWindowsOSLabelSplit(t *testing.T) {
+	assert.Equal(t, nodeconfig.WindowsOSLabelKey+"="+nodeconfig.WindowsOSLabelValue, nodeconfig.WindowsOSLabel)
+}
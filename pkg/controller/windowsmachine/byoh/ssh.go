@@ -0,0 +1,23 @@
+package byoh
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	core "k8s.io/api/core/v1"
+)
+
+// privateKeySecretKey is the key within a BYOH instance's SSH secret that holds the PEM-encoded private key.
+const privateKeySecretKey = "private-key"
+
+// signerFromSecret parses the PEM-encoded SSH private key stored in the given secret.
+func signerFromSecret(secret *core.Secret) (ssh.Signer, error) {
+	keyData, ok := secret.Data[privateKeySecretKey]
+	if !ok {
+		return nil, errors.Errorf("secret %s is missing key %s", secret.GetName(), privateKeySecretKey)
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse private key")
+	}
+	return signer, nil
+}
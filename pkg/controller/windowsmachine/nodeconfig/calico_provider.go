@@ -0,0 +1,65 @@
+package nodeconfig
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
+)
+
+const (
+	// CalicoWorkloadEndpointSubnet is the annotation applied by Calico for Windows with the pod subnet
+	// allocated to the node, analogous to HybridOverlaySubnet for OVN-Kubernetes.
+	CalicoWorkloadEndpointSubnet = "projectcalico.org/IPv4VXLANTunnelAddr"
+	// CalicoIPAMBlockAffinity is the annotation applied once Calico's IPAM controller has claimed an
+	// IPAMBlock for the node, analogous to HybridOverlayMac for OVN-Kubernetes.
+	CalicoIPAMBlockAffinity = "projectcalico.org/ipam-block-affinity"
+	// calicoConfigTemplate identifies the Calico variant of the cni-conf-template.ps1 payload script, which
+	// renders cni.conf to delegate pod routing to Calico's VXLAN tunnel.
+	calicoConfigTemplate = "calico"
+)
+
+// calicoProvider implements CNIProvider for clusters running Calico for Windows.
+type calicoProvider struct {
+	nc *nodeConfig
+}
+
+// hostSubnetAnnotation returns the annotation Calico writes with the node's pod subnet.
+func (p *calicoProvider) hostSubnetAnnotation() string {
+	return CalicoWorkloadEndpointSubnet
+}
+
+// configTemplate returns the cni-conf-template.ps1 variant this provider's cni.conf should be rendered from.
+func (p *calicoProvider) configTemplate() string {
+	return calicoConfigTemplate
+}
+
+// AwaitPrerequisites waits until the node object has the Calico WorkloadEndpoint subnet annotation.
+func (p *calicoProvider) AwaitPrerequisites(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(CalicoWorkloadEndpointSubnet)
+}
+
+// AwaitOverlayReady waits for the IPAMBlock affinity annotation, which confirms Calico's IPAM has claimed a
+// block for the node once the felix/node services started from the node's services manifest are ready.
+func (p *calicoProvider) AwaitOverlayReady(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(CalicoIPAMBlockAffinity)
+}
+
+// RenderConfig has the Windows VM render cni.conf locally from the Calico variant of the
+// cni-conf-template.ps1 payload script, using the host subnet and the service network CIDR.
+func (p *calicoProvider) RenderConfig(serviceCIDR, hostSubnet string) (string, error) {
+	configFile, err := p.nc.Windows.PopulateCNIConfig(p.configTemplate(), hostSubnet, serviceCIDR, p.nc.dsrEnabled)
+	if err != nil {
+		return "", errors.Wrap(err, "error rendering CNI config on Windows VM")
+	}
+	return configFile, nil
+}
+
+// OverlayServices returns the felix and calico-node services, whose IPAMBlock affinity annotation
+// AwaitOverlayReady waits on.
+func (p *calicoProvider) OverlayServices() []services.Service {
+	return []services.Service{
+		{Name: "calico-felix", Command: `C:\k\calico\felix.exe`, Dependencies: []string{"kubelet"}},
+		{Name: "calico-node", Command: `C:\k\calico\calico-node.exe`, Dependencies: []string{"calico-felix"}},
+	}
+}
@@ -0,0 +1,121 @@
+package nodeconfig
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	clientset "github.com/openshift/client-go/config/clientset/versioned"
+	"github.com/pkg/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// wmcoConfigMapNamespace is the namespace holding the WMCO ConfigMap that admins can use to override
+	// cluster endpoint discovery.
+	wmcoConfigMapNamespace = "openshift-windows-machine-config-operator"
+	// wmcoConfigMapName is the name of the WMCO ConfigMap that admins can use to override cluster endpoint
+	// discovery.
+	wmcoConfigMapName = "windows-machine-config-operator"
+	// clusterAddressOverrideKey is the WMCO ConfigMap key holding a user-supplied cluster address. Resolve
+	// uses it only when neither internal DNS nor the advertised load balancer address yields one.
+	clusterAddressOverrideKey = "clusterAddress"
+)
+
+// ClusterEndpointResolver discovers the cluster address Windows nodes should use to reach the
+// machine-config-server, trying a sequence of strategies until one succeeds. This allows clusters that front
+// kube-apiserver with a custom load balancer, or that don't use the `api-int.` naming convention, to still
+// bootstrap Windows nodes (WINC-398).
+type ClusterEndpointResolver struct {
+	configClient clientset.Interface
+	k8sclientset *kubernetes.Clientset
+}
+
+// NewClusterEndpointResolver returns a ClusterEndpointResolver backed by the given clients.
+func NewClusterEndpointResolver(configClient clientset.Interface, k8sclientset *kubernetes.Clientset) *ClusterEndpointResolver {
+	return &ClusterEndpointResolver{configClient: configClient, k8sclientset: k8sclientset}
+}
+
+// Resolve returns the cluster address Windows nodes should use to reach the machine-config-server, trying
+// each strategy in turn and returning the first one that succeeds.
+func (r *ClusterEndpointResolver) Resolve() (string, error) {
+	strategies := []func() (string, error){
+		r.internalDNS,
+		r.loadBalancerIP,
+		r.configMapOverride,
+	}
+
+	var errs []string
+	for _, strategy := range strategies {
+		addr, err := strategy()
+		if err == nil && addr != "" {
+			return addr, nil
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return "", errors.Errorf("unable to resolve cluster address by any known strategy: %s", strings.Join(errs, "; "))
+}
+
+// internalDNS resolves the cluster address from the infrastructure resource's APIServerInternalURL, which is
+// typically backed by internal DNS (e.g. api-int.<cluster domain>).
+func (r *ClusterEndpointResolver) internalDNS() (string, error) {
+	infra, err := r.configClient.ConfigV1().Infrastructures().Get(context.TODO(), "cluster", meta.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get cluster infrastructure resource")
+	}
+	if infra.Status.APIServerInternalURL == "" {
+		return "", errors.New("infrastructure resource has no APIServerInternalURL")
+	}
+	clusterEndpoint, err := url.Parse(infra.Status.APIServerInternalURL)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to parse the kubernetes API server endpoint")
+	}
+	return clusterEndpoint.Hostname(), nil
+}
+
+// loadBalancerIP falls back to the address the kube-apiserver advertises for in-cluster clients, which is
+// reachable even when the cluster fronts kube-apiserver with a custom load balancer that doesn't resolve
+// through the `api-int.` DNS name.
+func (r *ClusterEndpointResolver) loadBalancerIP() (string, error) {
+	var apiVersions meta.APIVersions
+	if err := r.k8sclientset.CoreV1().RESTClient().Get().AbsPath("/api").Do(context.TODO()).Into(&apiVersions); err != nil {
+		return "", errors.Wrap(err, "unable to query API server root for advertised addresses")
+	}
+	for _, cidr := range apiVersions.ServerAddressByClientCIDRs {
+		if cidr.ServerAddress != "" {
+			return stripPort(cidr.ServerAddress), nil
+		}
+	}
+	return "", errors.New("API server did not advertise a server address for any client CIDR")
+}
+
+// stripPort returns addr with any trailing ":<port>" removed, so loadBalancerIP returns a bare host like
+// internalDNS and configMapOverride do. ServerAddressByClientCIDR.ServerAddress may already be in host:port
+// form, and NewNodeConfig appends its own ":22623" to whatever discoverClusterAddress returns.
+func stripPort(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		// addr had no port to strip
+		return addr
+	}
+	return host
+}
+
+// configMapOverride lets a cluster admin provide an explicit cluster address via the WMCO ConfigMap, for
+// clusters whose naming or load balancer topology the other strategies cannot resolve.
+func (r *ClusterEndpointResolver) configMapOverride() (string, error) {
+	cm, err := r.k8sclientset.CoreV1().ConfigMaps(wmcoConfigMapNamespace).Get(context.TODO(), wmcoConfigMapName,
+		meta.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get WMCO ConfigMap")
+	}
+	addr, ok := cm.Data[clusterAddressOverrideKey]
+	if !ok || addr == "" {
+		return "", errors.New("WMCO ConfigMap has no cluster address override")
+	}
+	return addr, nil
+}
@@ -0,0 +1,130 @@
+package nodeconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	oconfig "github.com/openshift/api/config/v1"
+	configfake "github.com/openshift/client-go/config/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestClusterEndpointResolverInternalDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		infra   *oconfig.Infrastructure
+		wantErr bool
+		want    string
+	}{
+		{
+			name: "APIServerInternalURL set",
+			infra: &oconfig.Infrastructure{ObjectMeta: meta.ObjectMeta{Name: "cluster"},
+				Status: oconfig.InfrastructureStatus{APIServerInternalURL: "https://api-int.example.com:6443"}},
+			want: "api-int.example.com",
+		},
+		{
+			name:    "APIServerInternalURL empty",
+			infra:   &oconfig.Infrastructure{ObjectMeta: meta.ObjectMeta{Name: "cluster"}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewClusterEndpointResolver(configfake.NewSimpleClientset(test.infra), k8sfake.NewSimpleClientset())
+			addr, err := r.internalDNS()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, addr)
+		})
+	}
+}
+
+func TestClusterEndpointResolverConfigMapOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		cm      *core.ConfigMap
+		wantErr bool
+		want    string
+	}{
+		{
+			name: "override present",
+			cm: &core.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{Name: wmcoConfigMapName, Namespace: wmcoConfigMapNamespace},
+				Data:       map[string]string{clusterAddressOverrideKey: "10.0.0.1"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name: "override missing",
+			cm: &core.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{Name: wmcoConfigMapName, Namespace: wmcoConfigMapNamespace},
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewClusterEndpointResolver(configfake.NewSimpleClientset(), k8sfake.NewSimpleClientset(test.cm))
+			addr, err := r.configMapOverride()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, addr)
+		})
+	}
+}
+
+func TestClusterEndpointResolverConfigMapOverrideNotFound(t *testing.T) {
+	r := NewClusterEndpointResolver(configfake.NewSimpleClientset(), k8sfake.NewSimpleClientset())
+	_, err := r.configMapOverride()
+	assert.Error(t, err)
+}
+
+// TestClusterEndpointResolverLoadBalancerIP guards against the double-port bug where an advertised
+// host:port ServerAddress was returned verbatim and then had ":22623" appended by NewNodeConfig.
+func TestClusterEndpointResolverLoadBalancerIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverAddress string
+		want          string
+	}{
+		{name: "bare host", serverAddress: "10.0.0.1", want: "10.0.0.1"},
+		{name: "host:port", serverAddress: "10.0.0.1:6443", want: "10.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := json.Marshal(meta.APIVersions{
+				ServerAddressByClientCIDRs: []meta.ServerAddressByClientCIDR{
+					{ClientCIDR: "0.0.0.0/0", ServerAddress: test.serverAddress},
+				},
+			})
+			require.NoError(t, err)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write(body)
+			}))
+			defer server.Close()
+
+			k8sclientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+			require.NoError(t, err)
+
+			r := NewClusterEndpointResolver(configfake.NewSimpleClientset(), k8sclientset)
+			addr, err := r.loadBalancerIP()
+			require.NoError(t, err)
+			assert.Equal(t, test.want, addr)
+		})
+	}
+}
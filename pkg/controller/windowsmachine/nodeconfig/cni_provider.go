@@ -0,0 +1,132 @@
+package nodeconfig
+
+import (
+	"context"
+
+	oconfig "github.com/openshift/api/config/v1"
+	clientset "github.com/openshift/client-go/config/clientset/versioned"
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crclientcfg "sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
+)
+
+const (
+	// calicoNetworkType is the NetworkType reported by the cluster Network config when Calico for Windows is
+	// the configured CNI.
+	calicoNetworkType oconfig.NetworkType = "Calico"
+	// kubeOVNNetworkType is the NetworkType reported by the cluster Network config when Kube-OVN is the
+	// configured CNI.
+	kubeOVNNetworkType oconfig.NetworkType = "Kube-OVN"
+)
+
+// CNIProvider abstracts the network-specific steps required to wire a Windows node into the cluster's pod
+// network, so that nodeConfig.configureNetwork does not need to know whether the cluster runs
+// OVN-Kubernetes hybrid-overlay, Calico for Windows, or Kube-OVN.
+type CNIProvider interface {
+	// AwaitPrerequisites blocks until the node object carries the annotations this provider's control plane
+	// component is expected to write, which must be present before the Windows VM can be configured.
+	AwaitPrerequisites(node *core.Node) error
+	// AwaitOverlayReady blocks until the node object carries the annotation that signals the overlay
+	// networking component (started as part of the node's Windows services manifest) is ready for CNI
+	// configuration to proceed.
+	AwaitOverlayReady(node *core.Node) error
+	// RenderConfig renders the win-overlay/l2bridge CNI configuration for this provider and returns the path
+	// of the resulting config file.
+	RenderConfig(serviceCIDR, hostSubnet string) (string, error)
+	// OverlayServices returns the Windows services that implement this provider's pod-network overlay, in
+	// the order they must be started, for inclusion in the node's services manifest. AwaitOverlayReady
+	// blocks until the annotation(s) these services are expected to write are present.
+	OverlayServices() []services.Service
+}
+
+// hostSubnetAnnotated is implemented by CNIProviders whose host subnet is discovered from a node annotation.
+// nodeConfig.configureCNI uses it to look up the annotation to read before calling RenderConfig.
+type hostSubnetAnnotated interface {
+	hostSubnetAnnotation() string
+}
+
+// configTemplated is implemented by every CNIProvider's RenderConfig to select which network-type-specific
+// variant of the cni-conf-template.ps1 payload script the Windows VM should render cni.conf from.
+type configTemplated interface {
+	configTemplate() string
+}
+
+// discoverNetworkType discovers the NetworkType configured for the cluster, which determines which
+// CNIProvider implementation should be used to configure Windows nodes.
+func discoverNetworkType() (oconfig.NetworkType, error) {
+	cfg, err := crclientcfg.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get config to talk to kubernetes api server")
+	}
+
+	client, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get client from the given config")
+	}
+
+	clusterNetwork, err := client.ConfigV1().Networks().Get(context.TODO(), "cluster", meta.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get cluster network resource")
+	}
+	return oconfig.NetworkType(clusterNetwork.Status.NetworkType), nil
+}
+
+// newCNIProvider returns the CNIProvider implementation appropriate for the given cluster network type.
+func newCNIProvider(networkType oconfig.NetworkType, nc *nodeConfig) (CNIProvider, error) {
+	switch networkType {
+	case "", oconfig.NetworkTypeOVNKubernetes:
+		return &hybridOverlayProvider{nc: nc}, nil
+	case calicoNetworkType:
+		return &calicoProvider{nc: nc}, nil
+	case kubeOVNNetworkType:
+		return &kubeOVNProvider{nc: nc}, nil
+	default:
+		return nil, errors.Errorf("unsupported cluster network type %s", networkType)
+	}
+}
+
+// DiscoverNetworkType discovers the NetworkType configured for the cluster. It is exported so that callers
+// which need to know the cluster's CNIProvider before any per-node nodeConfig exists (e.g.
+// cmd/operator/main.go populating the cluster-wide services manifest) don't need to duplicate cluster Network
+// lookup logic.
+func DiscoverNetworkType() (oconfig.NetworkType, error) {
+	return discoverNetworkType()
+}
+
+// OverlayServicesFor returns the overlay services the CNIProvider for the given cluster network type
+// contributes to the node's services manifest. It is exported for callers that need a provider's overlay
+// services without constructing a per-node nodeConfig.
+func OverlayServicesFor(networkType oconfig.NetworkType) ([]services.Service, error) {
+	provider, err := newCNIProvider(networkType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return provider.OverlayServices(), nil
+}
+
+// DiscoverClusterServiceCIDR discovers the cluster's service network CIDR from the cluster Network config.
+// It is exported for callers (e.g. cmd/operator/main.go constructing the BYOH controller) that need the
+// service CIDR before any per-node nodeConfig exists.
+func DiscoverClusterServiceCIDR() (string, error) {
+	cfg, err := crclientcfg.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get config to talk to kubernetes api server")
+	}
+
+	client, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get client from the given config")
+	}
+
+	clusterNetwork, err := client.ConfigV1().Networks().Get(context.TODO(), "cluster", meta.GetOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get cluster network resource")
+	}
+	if len(clusterNetwork.Status.ServiceNetwork) == 0 {
+		return "", errors.New("cluster network resource has no service network CIDR")
+	}
+	return clusterNetwork.Status.ServiceNetwork[0], nil
+}
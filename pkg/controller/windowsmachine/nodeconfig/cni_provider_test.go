@@ -0,0 +1,35 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	oconfig "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCNIProvider(t *testing.T) {
+	nc := &nodeConfig{}
+	tests := []struct {
+		name        string
+		networkType oconfig.NetworkType
+		expected    interface{}
+	}{
+		{name: "empty defaults to hybrid-overlay", networkType: "", expected: &hybridOverlayProvider{}},
+		{name: "OVNKubernetes", networkType: oconfig.NetworkTypeOVNKubernetes, expected: &hybridOverlayProvider{}},
+		{name: "Calico", networkType: calicoNetworkType, expected: &calicoProvider{}},
+		{name: "Kube-OVN", networkType: kubeOVNNetworkType, expected: &kubeOVNProvider{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider, err := newCNIProvider(test.networkType, nc)
+			require.NoError(t, err)
+			assert.IsType(t, test.expected, provider)
+		})
+	}
+}
+
+func TestNewCNIProviderUnsupportedNetworkType(t *testing.T) {
+	_, err := newCNIProvider("SomeOtherCNI", &nodeConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+)
+
+func TestAddDSREnabledAnnotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		dsrEnabled bool
+		expected   string
+	}{
+		{name: "DSR enabled", dsrEnabled: true, expected: "true"},
+		{name: "DSR disabled", dsrEnabled: false, expected: "false"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nc := &nodeConfig{dsrEnabled: test.dsrEnabled, node: &core.Node{}}
+			nc.node.Annotations = map[string]string{}
+			nc.addDSREnabledAnnotation()
+			assert.Equal(t, test.expected, nc.node.Annotations[DSREnabledAnnotation])
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package nodeconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHostSubnetAnnotation guards configureCNI's type-switch on hostSubnetAnnotated: every CNIProvider that
+// discovers its host subnet from a node annotation must report the same annotation it waits on in
+// AwaitPrerequisites, so configureCNI reads the annotation that was actually populated.
+func TestHostSubnetAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider hostSubnetAnnotated
+		expected string
+	}{
+		{name: "hybrid-overlay", provider: &hybridOverlayProvider{}, expected: HybridOverlaySubnet},
+		{name: "Calico", provider: &calicoProvider{}, expected: CalicoWorkloadEndpointSubnet},
+		{name: "Kube-OVN", provider: &kubeOVNProvider{}, expected: KubeOVNPodSubnet},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.provider.hostSubnetAnnotation())
+		})
+	}
+}
+
+// TestConfigTemplateDiffersPerProvider guards against RenderConfig regressing to a single shared template:
+// each CNIProvider must pass its own network-type-specific variant into Windows.PopulateCNIConfig, so a
+// Calico or Kube-OVN cluster doesn't get the hybrid-overlay rendering of cni.conf.
+func TestConfigTemplateDiffersPerProvider(t *testing.T) {
+	providers := []configTemplated{
+		&hybridOverlayProvider{},
+		&calicoProvider{},
+		&kubeOVNProvider{},
+	}
+	seen := make(map[string]bool, len(providers))
+	for _, provider := range providers {
+		template := provider.configTemplate()
+		assert.NotEmpty(t, template)
+		assert.Falsef(t, seen[template], "template %q is used by more than one CNIProvider", template)
+		seen[template] = true
+	}
+}
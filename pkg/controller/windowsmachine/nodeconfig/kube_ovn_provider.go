@@ -0,0 +1,65 @@
+package nodeconfig
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
+)
+
+const (
+	// KubeOVNPodSubnet is the annotation applied by Kube-OVN with the pod subnet allocated to the node,
+	// analogous to HybridOverlaySubnet for OVN-Kubernetes.
+	KubeOVNPodSubnet = "ovn.kubernetes.io/pod_cidr"
+	// KubeOVNPodGatewayMac is the annotation applied by Kube-OVN with the gateway MAC for the node's subnet,
+	// analogous to HybridOverlayMac for OVN-Kubernetes.
+	KubeOVNPodGatewayMac = "ovn.kubernetes.io/pod_gateway_mac"
+	// kubeOVNConfigTemplate identifies the Kube-OVN variant of the cni-conf-template.ps1 payload script,
+	// which renders cni.conf to delegate pod routing to the Kube-OVN daemon's HNS network.
+	kubeOVNConfigTemplate = "kube-ovn"
+)
+
+// kubeOVNProvider implements CNIProvider for clusters running Kube-OVN.
+type kubeOVNProvider struct {
+	nc *nodeConfig
+}
+
+// hostSubnetAnnotation returns the annotation Kube-OVN writes with the node's pod subnet.
+func (p *kubeOVNProvider) hostSubnetAnnotation() string {
+	return KubeOVNPodSubnet
+}
+
+// configTemplate returns the cni-conf-template.ps1 variant this provider's cni.conf should be rendered from.
+func (p *kubeOVNProvider) configTemplate() string {
+	return kubeOVNConfigTemplate
+}
+
+// AwaitPrerequisites waits until the node object has the Kube-OVN pod subnet annotation.
+func (p *kubeOVNProvider) AwaitPrerequisites(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(KubeOVNPodSubnet)
+}
+
+// AwaitOverlayReady waits for the pod gateway MAC annotation, which Kube-OVN writes once the CNI daemon
+// started from the node's services manifest is ready, and which is required before CNI configuration can
+// start.
+func (p *kubeOVNProvider) AwaitOverlayReady(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(KubeOVNPodGatewayMac)
+}
+
+// RenderConfig has the Windows VM render cni.conf locally from the Kube-OVN variant of the
+// cni-conf-template.ps1 payload script, using the host subnet and the service network CIDR.
+func (p *kubeOVNProvider) RenderConfig(serviceCIDR, hostSubnet string) (string, error) {
+	configFile, err := p.nc.Windows.PopulateCNIConfig(p.configTemplate(), hostSubnet, serviceCIDR, p.nc.dsrEnabled)
+	if err != nil {
+		return "", errors.Wrap(err, "error rendering CNI config on Windows VM")
+	}
+	return configFile, nil
+}
+
+// OverlayServices returns the kube-ovn CNI daemon service, whose pod gateway MAC annotation
+// AwaitOverlayReady waits on.
+func (p *kubeOVNProvider) OverlayServices() []services.Service {
+	return []services.Service{
+		{Name: "kube-ovn-daemon", Command: `C:\k\kube-ovn-daemon.exe`, Dependencies: []string{"kubelet"}},
+	}
+}
@@ -3,8 +3,9 @@ package nodeconfig
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
-	"net/url"
+	"strconv"
 	"strings"
 
 	oconfig "github.com/openshift/api/config/v1"
@@ -19,9 +20,9 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/openshift/windows-machine-config-operator/pkg/cluster"
-	"github.com/openshift/windows-machine-config-operator/pkg/controller/payload"
 	"github.com/openshift/windows-machine-config-operator/pkg/controller/retry"
 	"github.com/openshift/windows-machine-config-operator/pkg/controller/windowsmachine/windows"
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
 	"github.com/openshift/windows-machine-config-operator/version"
 )
 
@@ -30,14 +31,33 @@ const (
 	HybridOverlaySubnet = "k8s.ovn.org/hybrid-overlay-node-subnet"
 	// HybridOverlayMac is an annotation applied by the hybrid-overlay
 	HybridOverlayMac = "k8s.ovn.org/hybrid-overlay-distributed-router-gateway-mac"
-	// WindowsOSLabel is the label that is applied by WMCB to identify the Windows nodes bootstrapped via WMCB
-	WindowsOSLabel = "node.openshift.io/os_id=Windows"
+	// WindowsOSLabel is the label that is applied by WMCB to identify the Windows nodes bootstrapped via WMCB,
+	// in key=value selector form for use as a meta.ListOptions.LabelSelector.
+	WindowsOSLabel = WindowsOSLabelKey + "=" + WindowsOSLabelValue
+	// WindowsOSLabelKey is the key of WindowsOSLabel, for use as a client.MatchingLabels key.
+	WindowsOSLabelKey = "node.openshift.io/os_id"
+	// WindowsOSLabelValue is the value of WindowsOSLabel, for use as a client.MatchingLabels value.
+	WindowsOSLabelValue = "Windows"
 	// WorkerLabel is the label that needs to be applied to the Windows node to make it worker node
 	WorkerLabel = "node-role.kubernetes.io/worker"
 	// VersionAnnotation indicates the version of WMCO that configured the node
 	VersionAnnotation = "windowsmachineconfig.openshift.io/version"
 	// PubKeyHashAnnotation corresponds to the public key present on the VM
 	PubKeyHashAnnotation = "windowsmachineconfig.openshift.io/pub-key-hash"
+	// DSREnabledAnnotation records whether the DSR probe found that this node's HNS/compute system supports
+	// Direct Server Return mode. kube-proxy's flags come from the windows-services ConfigMap manifest and are
+	// not set from this value.
+	DSREnabledAnnotation = "windowsmachineconfig.openshift.io/dsr-enabled"
+	// UsernameAnnotation identifies the SSH username used to configure a bring-your-own-host node. Combined
+	// with AddressAnnotation it lets setNode locate nodes that have no cloud Machine/ProviderID.
+	UsernameAnnotation = "windowsmachineconfig.openshift.io/username"
+	// AddressAnnotation identifies the address of a bring-your-own-host node, see UsernameAnnotation.
+	AddressAnnotation = "windowsmachineconfig.openshift.io/address"
+	// ServicesHashAnnotation records the checksum of the services Manifest last applied to this node, so
+	// reconcileServices can tell when the node's services have drifted from what is currently declared.
+	ServicesHashAnnotation = "windowsmachineconfig.openshift.io/services-hash"
+	// servicesConfigMapNamespace is the namespace the windows-services ConfigMaps are reconciled into.
+	servicesConfigMapNamespace = "openshift-windows-machine-config-operator"
 )
 
 // nodeConfig holds the information to make the given VM a kubernetes node. As of now, it holds the information
@@ -49,35 +69,36 @@ type nodeConfig struct {
 	windows.Windows
 	// Node holds the information related to node object
 	node *core.Node
-	// network holds the network information specific to the node
-	network *network
+	// cniProvider configures the CNI implementation running on the cluster for the node
+	cniProvider CNIProvider
+	// dsrEnabled indicates whether the Windows VM's HNS/compute system supports Direct Server Return mode for
+	// kube-proxy. It is populated by Configure and falls back to false (SNAT) when the probe fails.
+	dsrEnabled bool
+	// vxlanPort is the VXLAN port the node's services manifest is keyed and configured with
+	vxlanPort string
+	// servicesHash is the checksum of the services Manifest applied to the node by reconcileServices
+	servicesHash string
 	// publicKeyHash is the hash of the public key present on the VM
 	publicKeyHash string
 	// clusterServiceCIDR holds the service CIDR for cluster
 	clusterServiceCIDR string
 }
 
-// discoverKubeAPIServerEndpoint discovers the kubernetes api server endpoint
-func discoverKubeAPIServerEndpoint() (string, error) {
+// discoverClusterAddress resolves the cluster address Windows nodes should use to reach the
+// machine-config-server, via a ClusterEndpointResolver.
+func discoverClusterAddress(k8sclientset *kubernetes.Clientset) (string, error) {
 	cfg, err := crclientcfg.GetConfig()
 	if err != nil {
 		return "", errors.Wrap(err, "unable to get config to talk to kubernetes api server")
 	}
 
-	client, err := clientset.NewForConfig(cfg)
+	configClient, err := clientset.NewForConfig(cfg)
 	if err != nil {
 		return "", errors.Wrap(err, "unable to get client from the given config")
 	}
 
-	host, err := client.ConfigV1().Infrastructures().Get(context.TODO(), "cluster", meta.GetOptions{})
-	if err != nil {
-		return "", errors.Wrap(err, "unable to get cluster infrastructure resource")
-	}
-	// get API server internal url of format https://api-int.abc.devcluster.openshift.com:6443
-	if host.Status.APIServerInternalURL == "" {
-		return "", errors.Wrap(err, "could not get host name for the kubernetes api server")
-	}
-	return host.Status.APIServerInternalURL, nil
+	resolver := NewClusterEndpointResolver(configClient, k8sclientset)
+	return resolver.Resolve()
 }
 
 // NewNodeConfig creates a new instance of nodeConfig to be used by the caller.
@@ -89,13 +110,8 @@ func NewNodeConfig(clientset *kubernetes.Clientset, ipAddress, instanceID, machi
 
 	var err error
 	if nodeConfigCache.workerIgnitionEndPoint == "" {
-		var kubeAPIServerEndpoint string
 		// We couldn't find it in cache. Let's compute it now.
-		kubeAPIServerEndpoint, err = discoverKubeAPIServerEndpoint()
-		if err != nil {
-			return nil, errors.Wrap(err, "unable to find kube api server endpoint")
-		}
-		clusterAddress, err := getClusterAddr(kubeAPIServerEndpoint)
+		clusterAddress, err := discoverClusterAddress(clientset)
 		if err != nil {
 			return nil, errors.Wrap(err, "error getting cluster address")
 		}
@@ -114,28 +130,20 @@ func NewNodeConfig(clientset *kubernetes.Clientset, ipAddress, instanceID, machi
 		return nil, errors.Wrap(err, "error instantiating Windows instance from VM")
 	}
 
-	return &nodeConfig{k8sclientset: clientset, Windows: win, network: newNetwork(),
-		clusterServiceCIDR: clusterServiceCIDR, publicKeyHash: CreatePubKeyHashAnnotation(signer.PublicKey())}, nil
-}
+	nc := &nodeConfig{k8sclientset: clientset, Windows: win, vxlanPort: vxlanPort,
+		clusterServiceCIDR: clusterServiceCIDR, publicKeyHash: CreatePubKeyHashAnnotation(signer.PublicKey())}
 
-// getClusterAddr gets the cluster address associated with given kubernetes APIServerEndpoint.
-// For example: https://api-int.abc.devcluster.openshift.com:6443 gets translated to
-// api-int.abc.devcluster.openshift.com
-// TODO: Think if this needs to be removed as this is too restrictive. Imagine apiserver behind
-// 		a loadbalancer.
-// 		Jira story: https://issues.redhat.com/browse/WINC-398
-func getClusterAddr(kubeAPIServerEndpoint string) (string, error) {
-	clusterEndPoint, err := url.Parse(kubeAPIServerEndpoint)
+	networkType, err := discoverNetworkType()
 	if err != nil {
-		return "", errors.Wrap(err, "unable to parse the kubernetes API server endpoint")
+		return nil, errors.Wrap(err, "unable to determine cluster network type")
 	}
-	hostName := clusterEndPoint.Hostname()
-
-	// Check if hostname is valid
-	if !strings.HasPrefix(hostName, "api-int.") {
-		return "", fmt.Errorf("invalid API server url %s: expected hostname to start with `api-int.`", hostName)
+	cniProvider, err := newCNIProvider(networkType, nc)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to select CNI provider")
 	}
-	return hostName, nil
+	nc.cniProvider = cniProvider
+
+	return nc, nil
 }
 
 // Configure configures the Windows VM to make it a Windows worker node
@@ -147,6 +155,15 @@ func (nc *nodeConfig) Configure() error {
 	if err := nc.setNode(); err != nil {
 		return errors.Wrapf(err, "error getting node object for VM %s", nc.ID())
 	}
+	// Probe the Windows VM's HNS/compute system to see if it supports DSR mode for kube-proxy. Fall back to
+	// the existing SNAT path if the probe fails.
+	dsrEnabled, err := nc.Windows.SupportsDSR()
+	if err != nil {
+		log.V(1).Error(err, "unable to detect DSR capability, falling back to SNAT", "node", nc.node.GetName())
+		dsrEnabled = false
+	}
+	nc.dsrEnabled = dsrEnabled
+
 	// Now that basic kubelet configuration is complete, configure networking in the node
 	if err := nc.configureNetwork(); err != nil {
 		return errors.Wrap(err, "configuring node network failed")
@@ -160,6 +177,8 @@ func (nc *nodeConfig) Configure() error {
 	}
 	nc.addVersionAnnotation()
 	nc.addPubKeyHashAnnotation()
+	nc.addDSREnabledAnnotation()
+	nc.addServicesHashAnnotation()
 	node, err := nc.k8sclientset.CoreV1().Nodes().Update(context.TODO(), nc.node, meta.UpdateOptions{})
 	if err != nil {
 		return errors.Wrap(err, "error updating node labels and annotations")
@@ -172,35 +191,61 @@ func (nc *nodeConfig) Configure() error {
 // configureNetwork configures k8s networking in the node
 // we are assuming that the WindowsVM and node objects are valid
 func (nc *nodeConfig) configureNetwork() error {
-	// Wait until the node object has the hybrid overlay subnet annotation. Otherwise the hybrid-overlay will fail to
-	// start
-	if err := nc.waitForNodeAnnotation(HybridOverlaySubnet); err != nil {
-		return errors.Wrapf(err, "error waiting for %s node annotation for %s", HybridOverlaySubnet,
-			nc.node.GetName())
+	// Wait until the node object has the annotation the cluster's CNI control plane writes for this node.
+	// Otherwise the overlay networking component will fail to start.
+	if err := nc.cniProvider.AwaitPrerequisites(nc.node); err != nil {
+		return errors.Wrapf(err, "error waiting for network prerequisites for %s", nc.node.GetName())
 	}
 
-	// NOTE: Investigate if we need to introduce a interface wrt to the VM's networking configuration. This will
-	// become more clear with the outcome of https://issues.redhat.com/browse/WINC-343
-
-	// Configure the hybrid overlay in the Windows VM
-	if err := nc.Windows.ConfigureHybridOverlay(nc.node.GetName()); err != nil {
-		return errors.Wrapf(err, "error configuring hybrid overlay for %s", nc.node.GetName())
+	// Reconcile the node's Windows services (containerd, kubelet, the CNIProvider's overlay services,
+	// kube-proxy, windows_exporter) against the declared services manifest. This starts the overlay networking component and
+	// kube-proxy.
+	if err := nc.reconcileServices(); err != nil {
+		return errors.Wrapf(err, "error reconciling Windows services for %s", nc.node.GetName())
 	}
 
-	// Wait until the node object has the hybrid overlay MAC annotation. This is required for the CNI configuration to
-	// start.
-	if err := nc.waitForNodeAnnotation(HybridOverlayMac); err != nil {
-		return errors.Wrapf(err, "error waiting for %s node annotation for %s", HybridOverlayMac,
-			nc.node.GetName())
+	// Wait for the annotation that signals the overlay networking component is ready before CNI configuration
+	// can proceed.
+	if err := nc.cniProvider.AwaitOverlayReady(nc.node); err != nil {
+		return errors.Wrapf(err, "error waiting for overlay network for %s", nc.node.GetName())
 	}
 
 	// Configure CNI in the Windows VM
 	if err := nc.configureCNI(); err != nil {
 		return errors.Wrapf(err, "error configuring CNI for %s", nc.node.GetName())
 	}
-	// Start the kube-proxy service
-	if err := nc.Windows.ConfigureKubeProxy(nc.node.GetName(), nc.node.Annotations[HybridOverlaySubnet]); err != nil {
-		return errors.Wrapf(err, "error starting kube-proxy for %s", nc.node.GetName())
+	return nil
+}
+
+// reconcileServices fetches the services Manifest declared for this node's WMCO version and VXLAN port and
+// re-applies it to the Windows VM only if it has drifted from what was last applied, recording the applied
+// manifest's checksum for addServicesHashAnnotation to persist once the node is next updated.
+func (nc *nodeConfig) reconcileServices() error {
+	cm, err := nc.k8sclientset.CoreV1().ConfigMaps(servicesConfigMapNamespace).Get(context.TODO(),
+		services.ConfigMapName(version.Get(), nc.vxlanPort), meta.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "unable to get windows services ConfigMap")
+	}
+	data, ok := cm.Data[services.ManifestDataKey]
+	if !ok {
+		return errors.Errorf("windows services ConfigMap %s has no %s key", cm.GetName(), services.ManifestDataKey)
+	}
+	manifest := &services.Manifest{}
+	if err := json.Unmarshal([]byte(data), manifest); err != nil {
+		return errors.Wrap(err, "unable to unmarshal services manifest")
+	}
+	checksum, err := manifest.Checksum()
+	if err != nil {
+		return errors.Wrap(err, "unable to checksum services manifest")
+	}
+	nc.servicesHash = checksum
+
+	if nc.node.Annotations[ServicesHashAnnotation] == checksum {
+		// nothing has drifted, no need to re-push the manifest to the VM
+		return nil
+	}
+	if err := nc.Windows.ApplyServices(manifest); err != nil {
+		return errors.Wrap(err, "error applying Windows services")
 	}
 	return nil
 }
@@ -215,6 +260,16 @@ func (nc *nodeConfig) addPubKeyHashAnnotation() {
 	nc.node.Annotations[PubKeyHashAnnotation] = nc.publicKeyHash
 }
 
+// addDSREnabledAnnotation records on nc.node whether the DSR probe found DSR mode supported
+func (nc *nodeConfig) addDSREnabledAnnotation() {
+	nc.node.Annotations[DSREnabledAnnotation] = strconv.FormatBool(nc.dsrEnabled)
+}
+
+// addServicesHashAnnotation records the checksum of the services manifest applied to nc.node
+func (nc *nodeConfig) addServicesHashAnnotation() {
+	nc.node.Annotations[ServicesHashAnnotation] = nc.servicesHash
+}
+
 // setNode identifies the node from the instanceID provided and sets the node object in the nodeconfig.
 func (nc *nodeConfig) setNode() error {
 	err := wait.Poll(retry.Interval, retry.Timeout, func() (bool, error) {
@@ -228,9 +283,9 @@ func (nc *nodeConfig) setNode() error {
 			log.V(1).Error(err, "expected non-empty node list")
 			return false, nil
 		}
-		// get the node with given instance id
+		// get the node matching this nodeConfig's VM
 		for _, node := range nodes.Items {
-			if nc.ID() == getInstanceIDfromProviderID(node.Spec.ProviderID) {
+			if nc.matchesNode(&node) {
 				nc.node = &node
 				return true, nil
 			}
@@ -240,6 +295,29 @@ func (nc *nodeConfig) setNode() error {
 	return errors.Wrapf(err, "unable to find node for instanceID %s", nc.ID())
 }
 
+// matchesNode returns true if the given node corresponds to the VM this nodeConfig is configuring. Nodes
+// provisioned by a cloud Machine carry a ProviderID and are matched by the instanceID embedded in it. BYOH
+// nodes have no ProviderID, so they are matched by the username+address annotation the operator writes on
+// them instead.
+func (nc *nodeConfig) matchesNode(node *core.Node) bool {
+	if node.Spec.ProviderID != "" {
+		return nc.ID() == getInstanceIDfromProviderID(node.Spec.ProviderID)
+	}
+	username, address := node.Annotations[UsernameAnnotation], node.Annotations[AddressAnnotation]
+	if username == "" || address == "" {
+		return false
+	}
+	return nc.ID() == BYOHInstanceID(username, address)
+}
+
+// BYOHInstanceID formats the synthetic provider identifier used in place of a cloud ProviderID for
+// bring-your-own-host Windows instances, which have no Machine. Callers pass the result as the instanceID
+// argument to NewNodeConfig, and it must match the UsernameAnnotation/AddressAnnotation pair written on the
+// node so that setNode can locate it.
+func BYOHInstanceID(username, address string) string {
+	return fmt.Sprintf("byoh:///%s@%s", username, address)
+}
+
 // waitForNodeAnnotation checks if the node object has the given annotation and waits for retry.Interval seconds and
 // returns an error if the annotation does not appear in that time frame.
 func (nc *nodeConfig) waitForNodeAnnotation(annotation string) error {
@@ -266,26 +344,24 @@ func (nc *nodeConfig) waitForNodeAnnotation(annotation string) error {
 	return nil
 }
 
-// configureCNI populates the CNI config template and sends the config file location
-// for completing CNI configuration in the windows VM
+// configureCNI asks the node's CNIProvider to render cni.conf on the Windows VM and sends the resulting
+// config file location for completing CNI configuration in the windows VM. Rendering happens on the VM
+// itself via the cni-conf-template.ps1 payload script, so there is no local temp file to clean up here.
 func (nc *nodeConfig) configureCNI() error {
-	// set the hostSubnet value in the network struct
-	if err := nc.network.setHostSubnet(nc.node.Annotations[HybridOverlaySubnet]); err != nil {
-		return errors.Wrapf(err, "error populating host subnet in node network")
+	// the host subnet annotation to read depends on which CNIProvider is configuring this node
+	var hostSubnet string
+	if hsa, ok := nc.cniProvider.(hostSubnetAnnotated); ok {
+		hostSubnet = nc.node.Annotations[hsa.hostSubnetAnnotation()]
 	}
-	// populate the CNI config file with the host subnet and the service network CIDR
-	configFile, err := nc.network.populateCniConfig(nc.clusterServiceCIDR, payload.CNIConfigTemplatePath)
+	// render the CNI config file on the Windows VM with the host subnet and the service network CIDR
+	configFile, err := nc.cniProvider.RenderConfig(nc.clusterServiceCIDR, hostSubnet)
 	if err != nil {
-		return errors.Wrapf(err, "error populating CNI config file %s", configFile)
+		return errors.Wrapf(err, "error rendering CNI config file %s", configFile)
 	}
 	// configure CNI in the Windows VM
 	if err = nc.Windows.ConfigureCNI(configFile); err != nil {
 		return errors.Wrapf(err, "error configuring CNI for %s", nc.node.GetName())
 	}
-	if err = nc.network.cleanupTempConfig(configFile); err != nil {
-		log.Error(err, " error deleting temp CNI config", "file",
-			configFile)
-	}
 	return nil
 }
 
@@ -0,0 +1,61 @@
+package nodeconfig
+
+import (
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+
+	"github.com/openshift/windows-machine-config-operator/pkg/services"
+)
+
+// hybridOverlayConfigTemplate identifies the hybrid-overlay variant of the cni-conf-template.ps1 payload
+// script, which renders cni.conf to delegate pod routing to the hybrid-overlay's HNS network.
+const hybridOverlayConfigTemplate = "hybrid-overlay"
+
+// hybridOverlayProvider implements CNIProvider for clusters running OVN-Kubernetes, where the Windows node's
+// pod networking is configured by the hybrid-overlay.
+type hybridOverlayProvider struct {
+	nc *nodeConfig
+}
+
+// configTemplate returns the cni-conf-template.ps1 variant this provider's cni.conf should be rendered from.
+func (p *hybridOverlayProvider) configTemplate() string {
+	return hybridOverlayConfigTemplate
+}
+
+// hostSubnetAnnotation returns the annotation the hybrid-overlay control plane writes with the node's pod
+// subnet.
+func (p *hybridOverlayProvider) hostSubnetAnnotation() string {
+	return HybridOverlaySubnet
+}
+
+// AwaitPrerequisites waits until the node object has the hybrid overlay subnet annotation. Otherwise the
+// hybrid-overlay will fail to start.
+func (p *hybridOverlayProvider) AwaitPrerequisites(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(HybridOverlaySubnet)
+}
+
+// AwaitOverlayReady waits for the hybrid overlay MAC annotation, which the hybrid-overlay-node service
+// started from the node's services manifest writes once it is ready, and which is required for CNI
+// configuration to start.
+func (p *hybridOverlayProvider) AwaitOverlayReady(node *core.Node) error {
+	return p.nc.waitForNodeAnnotation(HybridOverlayMac)
+}
+
+// RenderConfig has the Windows VM render cni.conf locally from the hybrid-overlay variant of the
+// cni-conf-template.ps1 payload script, using the host subnet and the service network CIDR. When the node's
+// HNS supports DSR, the rendered config advertises the DirectServerReturn capability so pods can advertise
+// DSR-eligible service backends.
+func (p *hybridOverlayProvider) RenderConfig(serviceCIDR, hostSubnet string) (string, error) {
+	configFile, err := p.nc.Windows.PopulateCNIConfig(p.configTemplate(), hostSubnet, serviceCIDR, p.nc.dsrEnabled)
+	if err != nil {
+		return "", errors.Wrap(err, "error rendering CNI config on Windows VM")
+	}
+	return configFile, nil
+}
+
+// OverlayServices returns the hybrid-overlay-node service, whose MAC annotation AwaitOverlayReady waits on.
+func (p *hybridOverlayProvider) OverlayServices() []services.Service {
+	return []services.Service{
+		{Name: "hybrid-overlay-node", Command: `C:\k\hybrid-overlay-node.exe`, Dependencies: []string{"kubelet"}},
+	}
+}
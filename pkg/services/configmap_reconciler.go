@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ManifestDataKey is the key under which the JSON-encoded Manifest is stored in the ConfigMap.
+const ManifestDataKey = "manifest"
+
+// ConfigMapReconciler renders a Manifest into a versioned ConfigMap and keeps it in sync with the running
+// WMCO version and VXLAN port.
+type ConfigMapReconciler struct {
+	client    client.Client
+	namespace string
+}
+
+// NewConfigMapReconciler returns a ConfigMapReconciler that stores manifests in the given namespace.
+func NewConfigMapReconciler(client client.Client, namespace string) *ConfigMapReconciler {
+	return &ConfigMapReconciler{client: client, namespace: namespace}
+}
+
+// Reconcile ensures the ConfigMap for the given VXLAN port and the running WMCO version exists and matches
+// the generated Manifest, creating or updating it as needed, and returns the resulting Manifest. overlayServices
+// and dsrEnabled are forwarded to GenerateManifest so the manifest reflects the cluster's configured CNIProvider
+// and DSR capability.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, vxlanPort string, dsrEnabled bool,
+	overlayServices []Service, verbose bool) (*Manifest, error) {
+	manifest := GenerateManifest(vxlanPort, dsrEnabled, overlayServices, verbose)
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal services manifest")
+	}
+
+	cm := &core.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      ConfigMapName(manifest.WMCOVersion, vxlanPort),
+			Namespace: r.namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.client, cm, func() error {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[ManifestDataKey] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to reconcile windows services ConfigMap")
+	}
+	return manifest, nil
+}
@@ -0,0 +1,95 @@
+// Package services declares the Windows services WMCO installs on every node as a single versioned manifest,
+// so that node configuration can be driven from a ConfigMap instead of a sequence of imperative, one-shot
+// calls.
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/openshift/windows-machine-config-operator/version"
+)
+
+// Service describes a single Windows service WMCO manages on a node.
+type Service struct {
+	// Name is the Windows service name.
+	Name string `json:"name"`
+	// Command is the path to the service's executable.
+	Command string `json:"command"`
+	// Args are the command line arguments the service is started with.
+	Args []string `json:"args,omitempty"`
+	// Dependencies are the names of services that must already be running before this one is started.
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// Manifest is the versioned, declarative definition of every Windows service WMCO installs on a node.
+type Manifest struct {
+	// WMCOVersion is the version of WMCO that generated this manifest.
+	WMCOVersion string `json:"wmcoVersion"`
+	// VXLANPort is the VXLAN port kube-proxy was configured with. The overlay services' arguments do not
+	// include it.
+	VXLANPort string `json:"vxlanPort"`
+	// Services are the services that make up this manifest, in the order they must be started.
+	Services []Service `json:"services"`
+}
+
+// GenerateManifest renders the full set of Windows services WMCO installs: kubelet, the given CNI overlay
+// services, kube-proxy, windows_exporter, and containerd. overlayServices is the CNIProvider-specific set of
+// services (e.g. hybrid-overlay-node, or Calico's felix/calico-node) that must be running before kube-proxy
+// can start; kube-proxy is made to depend on the last of them. Passing dsrEnabled starts kube-proxy in Direct
+// Server Return mode instead of the default SNAT mode. Passing verbose enables verbose logging on the
+// services that support it, so flag changes like this can roll out without rebuilding the VM.
+func GenerateManifest(vxlanPort string, dsrEnabled bool, overlayServices []Service, verbose bool) *Manifest {
+	logLevel := "2"
+	if verbose {
+		logLevel = "4"
+	}
+
+	kubeProxyArgs := []string{"--vxlan-port=" + vxlanPort, "--v=" + logLevel}
+	if dsrEnabled {
+		kubeProxyArgs = append(kubeProxyArgs, "--enable-dsr=true", "--feature-gates=WinDSR=true,WinOverlay=true")
+	}
+	kubeProxyDependencies := []string{"kubelet"}
+
+	svcs := []Service{
+		{Name: "containerd", Command: `C:\k\containerd.exe`},
+		{Name: "kubelet", Command: `C:\k\kubelet.exe`, Args: []string{"--v=" + logLevel},
+			Dependencies: []string{"containerd"}},
+	}
+	for _, overlayService := range overlayServices {
+		overlayService.Args = append(append([]string{}, overlayService.Args...), "--v="+logLevel)
+		svcs = append(svcs, overlayService)
+		kubeProxyDependencies = []string{overlayService.Name}
+	}
+	svcs = append(svcs,
+		Service{Name: "kube-proxy", Command: `C:\k\kube-proxy.exe`, Args: kubeProxyArgs,
+			Dependencies: kubeProxyDependencies},
+		Service{Name: "windows_exporter", Command: `C:\k\windows_exporter.exe`},
+	)
+
+	return &Manifest{
+		WMCOVersion: version.Get(),
+		VXLANPort:   vxlanPort,
+		Services:    svcs,
+	}
+}
+
+// Checksum returns the sha256 checksum of the manifest, used to detect drift between what was last applied to
+// a node and what is currently declared.
+func (m *Manifest) Checksum() (string, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to marshal services manifest")
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// ConfigMapName returns the name of the ConfigMap a manifest for the given WMCO version and VXLAN port is
+// stored under. Keying the name this way means an upgrade lands in a new ConfigMap rather than mutating one
+// that existing nodes may still be reconciling against.
+func ConfigMapName(wmcoVersion, vxlanPort string) string {
+	return fmt.Sprintf("windows-services-%s-%s", wmcoVersion, vxlanPort)
+}
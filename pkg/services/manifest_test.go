@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serviceNamed(t *testing.T, m *Manifest, name string) Service {
+	t.Helper()
+	for _, svc := range m.Services {
+		if svc.Name == name {
+			return svc
+		}
+	}
+	require.Failf(t, "service not found", "manifest has no service named %s", name)
+	return Service{}
+}
+
+func TestGenerateManifestOverlayServices(t *testing.T) {
+	overlay := []Service{
+		{Name: "calico-felix", Command: `C:\k\calico\felix.exe`, Dependencies: []string{"kubelet"}},
+		{Name: "calico-node", Command: `C:\k\calico\calico-node.exe`, Dependencies: []string{"calico-felix"}},
+	}
+	m := GenerateManifest("4789", false, overlay, false)
+
+	felix := serviceNamed(t, m, "calico-felix")
+	assert.Equal(t, []string{"kubelet"}, felix.Dependencies)
+	node := serviceNamed(t, m, "calico-node")
+	assert.Equal(t, []string{"calico-felix"}, node.Dependencies)
+
+	kubeProxy := serviceNamed(t, m, "kube-proxy")
+	assert.Equal(t, []string{"calico-node"}, kubeProxy.Dependencies, "kube-proxy should depend on the last overlay service")
+}
+
+func TestGenerateManifestDSR(t *testing.T) {
+	tests := []struct {
+		name          string
+		dsrEnabled    bool
+		expectedFlags []string
+	}{
+		{
+			name:       "DSR enabled",
+			dsrEnabled: true,
+			expectedFlags: []string{"--vxlan-port=4789", "--v=2", "--enable-dsr=true",
+				"--feature-gates=WinDSR=true,WinOverlay=true"},
+		},
+		{
+			name:          "DSR disabled",
+			dsrEnabled:    false,
+			expectedFlags: []string{"--vxlan-port=4789", "--v=2"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := GenerateManifest("4789", test.dsrEnabled, nil, false)
+			kubeProxy := serviceNamed(t, m, "kube-proxy")
+			assert.Equal(t, test.expectedFlags, kubeProxy.Args)
+		})
+	}
+}
+
+func TestGenerateManifestVerbose(t *testing.T) {
+	m := GenerateManifest("4789", false, nil, true)
+	kubelet := serviceNamed(t, m, "kubelet")
+	assert.Contains(t, kubelet.Args, "--v=4")
+}
+
+func TestManifestChecksumStableAndSensitiveToContent(t *testing.T) {
+	m1 := GenerateManifest("4789", false, nil, false)
+	m2 := GenerateManifest("4789", false, nil, false)
+	sum1, err := m1.Checksum()
+	require.NoError(t, err)
+	sum2, err := m2.Checksum()
+	require.NoError(t, err)
+	assert.Equal(t, sum1, sum2, "checksums of identical manifests should match")
+
+	m3 := GenerateManifest("4789", true, nil, false)
+	sum3, err := m3.Checksum()
+	require.NoError(t, err)
+	assert.NotEqual(t, sum1, sum3, "enabling DSR should change the manifest checksum")
+}
+
+func TestConfigMapName(t *testing.T) {
+	assert.Equal(t, "windows-services-1.2.3-4789", ConfigMapName("1.2.3", "4789"))
+}